@@ -1,7 +1,14 @@
 package main
 
 import (
+    "context"
+    "log"
+    "os"
+    "os/signal"
+    "syscall"
+
     "../tally"
+    "../tally/outputs"
 )
 
 func main() {
@@ -11,5 +18,21 @@ func main() {
     if err != nil {
         panic(err)
     }
-    server.Loop()
+    sinks, err := outputs.BuildFromConfig(cfg)
+    if err != nil {
+        panic(err)
+    }
+    server.SetOutputs(sinks)
+
+    signals := make(chan os.Signal, 1)
+    signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+    go func() {
+        sig := <-signals
+        log.Printf("received %s, shutting down", sig)
+        if err := server.Shutdown(context.Background()); err != nil {
+            log.Printf("ERROR: shutdown: %s", err)
+        }
+    }()
+
+    server.Loop(context.Background())
 }
@@ -0,0 +1,44 @@
+package tally
+
+import (
+    "context"
+    "net"
+    "testing"
+    "time"
+)
+
+// TestAggregateShutsDownUnderCancellation guards against a race between
+// RunReceiver's exit and Aggregate's shutdown handshake over the
+// controlChannels: if a receiver could observe cancellation and return on
+// its own, Aggregate could be left blocked forever sending or receiving on
+// that receiver's controlChannel during its final drain. Run repeatedly
+// since the race, when present, doesn't reproduce on every trial.
+func TestAggregateShutsDownUnderCancellation(t *testing.T) {
+    for trial := 0; trial < 30; trial++ {
+        conn, err := net.ListenUDP("udp", &net.UDPAddr{})
+        if err != nil {
+            t.Fatalf("failed to listen: %s", err)
+        }
+
+        ctx, cancel := context.WithCancel(context.Background())
+        snapchan := Aggregate(ctx, conn, 2, 10*time.Millisecond)
+
+        // Let a couple of flush cycles run before tearing down.
+        <-snapchan
+        <-snapchan
+        cancel()
+
+        done := make(chan struct{})
+        go func() {
+            for range(snapchan) {
+            }
+            close(done)
+        }()
+
+        select {
+        case <-done:
+        case <-time.After(2 * time.Second):
+            t.Fatalf("trial %d: Aggregate did not shut down within 2s of cancellation", trial)
+        }
+    }
+}
@@ -0,0 +1,51 @@
+package tally
+
+import (
+    "math"
+    "math/rand"
+    "time"
+)
+
+// Backoff implements the standard grpc-style exponential backoff with
+// jitter: delay = min(maxDelay, baseDelay * factor^retries), randomized by
+// +/- jitter as a fraction of the computed delay. Reset() should be called
+// after a successful attempt so the next failure starts over at baseDelay.
+type Backoff struct {
+    BaseDelay time.Duration
+    MaxDelay time.Duration
+    Factor float64
+    Jitter float64
+
+    retries int
+}
+
+// DefaultBackoff returns a Backoff using this package's defaults: a 1s base
+// delay, 1.6x growth factor, 20% jitter, and a 120s ceiling.
+func DefaultBackoff() *Backoff {
+    return &Backoff{
+        BaseDelay: time.Second,
+        MaxDelay: 120 * time.Second,
+        Factor: 1.6,
+        Jitter: 0.2,
+    }
+}
+
+// Next returns the delay to wait before the next retry and advances the
+// backoff's retry count.
+func (backoff *Backoff) Next() time.Duration {
+    delay := float64(backoff.BaseDelay) * math.Pow(backoff.Factor, float64(backoff.retries))
+    if max := float64(backoff.MaxDelay); delay > max {
+        delay = max
+    }
+    backoff.retries += 1
+    delay *= 1 + backoff.Jitter*(rand.Float64()*2 - 1)
+    if delay < 0 {
+        delay = 0
+    }
+    return time.Duration(delay)
+}
+
+// Reset clears the retry count, e.g. after a successful attempt.
+func (backoff *Backoff) Reset() {
+    backoff.retries = 0
+}
@@ -7,6 +7,7 @@ import (
     "fmt"
     "io"
     "os"
+    "regexp"
     "strconv"
     "strings"
     "time"
@@ -84,13 +85,47 @@ func (config Config) GetBoolean(section string, name string) (result bool, err e
     return
 }
 
-// GetSeconds is GetValue converted to a time.Duration. The implied unit for the
-// value is seconds.
+// GetFloat is GetValue passed through strconv.ParseFloat.
+func (config Config) GetFloat(section string, name string) (value float64, err error) {
+    strValue, err := config.GetValue(section, name)
+    if err != nil { return }
+    return strconv.ParseFloat(strValue, 64)
+}
+
+// GetDuration is GetValue passed through time.ParseDuration, e.g. "500ms" or
+// "2m".
+func (config Config) GetDuration(section string, name string) (duration time.Duration, err error) {
+    strValue, err := config.GetValue(section, name)
+    if err != nil { return }
+    return time.ParseDuration(strValue)
+}
+
+// GetStringList is GetValue split on commas, trimming whitespace around each
+// element and dropping empty ones.
+func (config Config) GetStringList(section string, name string) (values []string, err error) {
+    strValue, err := config.GetValue(section, name)
+    if err != nil { return }
+    for _, part := range(strings.Split(strValue, ",")) {
+        if part = strings.TrimSpace(part); part != "" {
+            values = append(values, part)
+        }
+    }
+    return
+}
+
+// GetSeconds is GetValue converted to a time.Duration. Values are parsed as
+// Go duration strings ("500ms", "2m") first; if that fails, they fall back to
+// being interpreted as a plain number of seconds, for backward compatibility
+// with existing config files.
 func (config Config) GetSeconds(section string, name string) (duration time.Duration, err error) {
-    // TODO: parse as float
-    seconds, err := config.GetInt(section, name)
+    strValue, err := config.GetValue(section, name)
     if err != nil { return }
-    duration = time.Duration(seconds) * time.Second
+    if duration, err = time.ParseDuration(strValue); err == nil {
+        return
+    }
+    var seconds float64
+    if seconds, err = strconv.ParseFloat(strValue, 64); err != nil { return }
+    duration = time.Duration(seconds * float64(time.Second))
     return
 }
 
@@ -176,6 +211,51 @@ func (parser *ConfigParser) ParseAssignment(line string) error {
         return parser.Error("name required for assignment")
     }
     value := strings.TrimSpace(parts[1])
-    (*parser.config)[parser.section][name] = value
+    expanded, err := parser.expand(value, make(map[string] bool))
+    if err != nil {
+        return parser.Error(err.Error())
+    }
+    (*parser.config)[parser.section][name] = expanded
     return nil
 }
+
+var interpolationPattern = regexp.MustCompile(`\$\{([^}]+)\}`)
+
+// expand replaces every ${ENV_VAR} or ${section.name} reference in value.
+// ${section.name} references are resolved against values already parsed
+// earlier in the file. seen tracks the section.name references currently
+// being expanded so a cycle is reported as an error instead of recursing
+// forever.
+func (parser *ConfigParser) expand(value string, seen map[string] bool) (string, error) {
+    var expandErr error
+    expanded := interpolationPattern.ReplaceAllStringFunc(value, func(match string) string {
+        if expandErr != nil { return match }
+        ref := match[2 : len(match) - 1]
+        if !strings.Contains(ref, ".") {
+            return os.Getenv(ref)
+        }
+        refParts := strings.SplitN(ref, ".", 2)
+        section, name := refParts[0], refParts[1]
+        if seen[ref] {
+            expandErr = fmt.Errorf("cyclic config reference: %s", ref)
+            return match
+        }
+        raw, ok := (*parser.config)[section][name]
+        if !ok {
+            expandErr = fmt.Errorf("no such config reference: %s", ref)
+            return match
+        }
+        seen[ref] = true
+        resolved, err := parser.expand(raw, seen)
+        delete(seen, ref)
+        if err != nil {
+            expandErr = err
+            return match
+        }
+        return resolved
+    })
+    if expandErr != nil {
+        return "", expandErr
+    }
+    return expanded, nil
+}
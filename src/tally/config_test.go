@@ -1,6 +1,9 @@
 package tally
 
-import "testing"
+import (
+	"os"
+	"testing"
+)
 
 func AssertSyntaxError(t *testing.T, err error) {
 	if err == nil {
@@ -135,3 +138,58 @@ func TestParseLine(t *testing.T) {
 	parser.AssertParseLine(t, "x=1")
 	parser.AssertAssigned(t, "test", "x", "1")
 }
+
+func TestGetSeconds(t *testing.T) {
+	config := make(Config)
+	config.AddSection("test")
+	config["test"]["legacy"] = "5"
+	config["test"]["duration"] = "500ms"
+
+	if duration, err := config.GetSeconds("test", "legacy"); err != nil || duration != 5e9 {
+		t.Errorf("expected 5s, got %s (err %v)", duration, err)
+	}
+	if duration, err := config.GetSeconds("test", "duration"); err != nil || duration.String() != "500ms" {
+		t.Errorf("expected 500ms, got %s (err %v)", duration, err)
+	}
+}
+
+func TestGetStringList(t *testing.T) {
+	config := make(Config)
+	config.AddSection("test")
+	config["test"]["outputs"] = "graphite, nats ,,influxdb"
+
+	values, err := config.GetStringList("test", "outputs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"graphite", "nats", "influxdb"}
+	if len(values) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, values)
+	}
+	for i := range(expected) {
+		if values[i] != expected[i] {
+			t.Errorf("expected %v, got %v", expected, values)
+		}
+	}
+}
+
+func TestParseAssignmentInterpolation(t *testing.T) {
+	os.Setenv("TALLY_TEST_SECRET", "hunter2")
+	defer os.Unsetenv("TALLY_TEST_SECRET")
+
+	config := make(Config)
+	parser := ConfigParser{config: &config}
+
+	config.AddSection("test")
+	parser.section = "test"
+	parser.AssertParseAssignment(t, "secret = ${TALLY_TEST_SECRET}")
+	parser.AssertAssigned(t, "test", "secret", "hunter2")
+
+	parser.AssertParseAssignment(t, "base = prod")
+	parser.AssertParseAssignment(t, "alias = ${test.base}.tallier")
+	parser.AssertAssigned(t, "test", "alias", "prod.tallier")
+
+	config["test"]["a"] = "${test.b}"
+	config["test"]["b"] = "${test.a}"
+	AssertSyntaxError(t, parser.ParseAssignment("c = ${test.a}"))
+}
@@ -1,6 +1,7 @@
 package tally
 
 import (
+    "context"
     "fmt"
     "log"
     "net/http"
@@ -45,34 +46,49 @@ func (harold *Harold) Heartbeat(tag string, interval time.Duration) (*http.Respo
 
 // HeartMonitor returns a channel for the caller to send harold heartbeats to.
 // It spins off a goroutine so the heartbeat channel never blocks, even if the
-// harold service is not responding.
-func (harold *Harold) HeartMonitor(tag string) (intervals chan time.Duration) {
+// harold service is not responding; failed heartbeats are retried with
+// exponential backoff rather than waiting for the next interval to come in.
+// The goroutine exits once ctx is cancelled.
+func (harold *Harold) HeartMonitor(ctx context.Context, tag string) (intervals chan time.Duration) {
     intervals = make(chan time.Duration)
     go func() {
         var alive *time.Duration // most recent interval pending to be sent
         waiting := false // whether we're waiting on a previous heartbeat send
+        var retryAfter <-chan time.Time
+        backoff := DefaultBackoff()
 
         // channel for notifying end of asynchronous heartbeat RPC
         err := make(chan error)
 
+        trySend := func() {
+            if alive == nil || waiting { return }
+            log.Printf("sending heartbeat to harold")
+            waiting = true
+            go func(i time.Duration) {
+                _, x := harold.Heartbeat(tag, i)
+                err <- x
+            }(*alive)
+        }
+
         for {
             select {
             case interval := <-intervals:
                 alive = &interval
+                trySend()
             case e := <-err:
+                waiting = false
                 if e != nil {
                     log.Printf("ERROR: harold heartbeat failed: %#v", e)
+                    retryAfter = time.After(backoff.Next())
+                } else {
+                    backoff.Reset()
+                    alive = nil
                 }
-                waiting = false
-            }
-            if alive != nil && !waiting {
-                log.Printf("sending heartbeat to harold")
-                go func(i time.Duration) {
-                    _, x := harold.Heartbeat(tag, i)
-                    err <- x
-                }(*alive)
-                waiting = true
-                alive = nil
+            case <-retryAfter:
+                retryAfter = nil
+                trySend()
+            case <-ctx.Done():
+                return
             }
         }
     }()
@@ -0,0 +1,128 @@
+package tally
+
+import (
+    "fmt"
+    "sort"
+    "strconv"
+)
+
+// sampleTypeCode returns the wire-format type suffix (e.g. "c", "ms") for
+// valueType, the inverse of the switch in ParseSample.
+func sampleTypeCode(valueType SampleType) string {
+    switch valueType {
+    case TIMER:
+        return "ms"
+    case COUNTER:
+        return "c"
+    case GAUGE:
+        return "g"
+    case HISTOGRAM:
+        return "h"
+    case SET:
+        return "s"
+    case METER:
+        return "m"
+    }
+    return ""
+}
+
+// AppendTo appends this sample's wire representation (e.g. "key:42|c" or
+// "key:+5|g|#region:us") to buf and returns the extended slice.
+func (sample Sample) AppendTo(buf []byte) []byte {
+    buf = append(buf, sample.key...)
+    buf = append(buf, ':')
+    if sample.valueType == SET {
+        buf = append(buf, '"')
+        buf = append(buf, sample.stringValue...)
+        buf = append(buf, '"')
+    } else {
+        if sample.valueType == GAUGE && sample.deltaSign > 0 {
+            buf = append(buf, '+')
+        }
+        buf = strconv.AppendFloat(buf, sample.value, 'f', -1, 64)
+    }
+    buf = append(buf, '|')
+    buf = append(buf, sampleTypeCode(sample.valueType)...)
+    if sample.sampleRate != 0 && sample.sampleRate != 1.0 {
+        buf = append(buf, '@')
+        buf = strconv.AppendFloat(buf, sample.sampleRate, 'f', -1, 64)
+    }
+    if len(sample.tags) > 0 {
+        buf = append(buf, '|', '#')
+        names := make([]string, 0, len(sample.tags))
+        for name := range(sample.tags) {
+            names = append(names, name)
+        }
+        sort.Strings(names)
+        for i, name := range(names) {
+            if i > 0 {
+                buf = append(buf, ',')
+            }
+            buf = append(buf, name...)
+            if value := sample.tags[name]; value != "" {
+                buf = append(buf, ':')
+                buf = append(buf, value...)
+            }
+        }
+    }
+    return buf
+}
+
+// Marshal renders the statgram as wire-format text, one newline-terminated
+// line per sample.
+func (statgram Statgram) Marshal() []byte {
+    buf := make([]byte, 0, len(statgram) * 32)
+    for _, sample := range(statgram) {
+        buf = sample.AppendTo(buf)
+        buf = append(buf, '\n')
+    }
+    return buf
+}
+
+// MarshalCompressed renders the statgram the same way as Marshal, but
+// whenever a line shares more than 3 leading bytes with the line before it,
+// replaces that shared prefix with the "^XX" hex-length escape Parse already
+// knows how to expand. This mirrors the prefix compression some statsd
+// relays use to shrink repetitive metric names on the wire.
+func (statgram Statgram) MarshalCompressed() []byte {
+    buf := make([]byte, 0, len(statgram) * 32)
+    previous := ""
+    for _, sample := range(statgram) {
+        line := string(sample.AppendTo(nil))
+
+        prefixLength := commonPrefixLength(previous, line)
+        if prefixLength > 0xff {
+            prefixLength = 0xff
+        }
+        // Parse only expands the escape when prefixLength < len(previous),
+        // so never emit one that would make a fully-repeated line un-expand.
+        if prefixLength >= len(previous) && len(previous) > 0 {
+            prefixLength = len(previous) - 1
+        }
+
+        if prefixLength > 3 {
+            buf = append(buf, '^')
+            buf = append(buf, fmt.Sprintf("%02x", prefixLength)...)
+            buf = append(buf, line[prefixLength:]...)
+        } else {
+            buf = append(buf, line...)
+        }
+        buf = append(buf, '\n')
+        previous = line
+    }
+    return buf
+}
+
+// commonPrefixLength returns the length of the longest common prefix of a
+// and b.
+func commonPrefixLength(a string, b string) int {
+    max := len(a)
+    if len(b) < max {
+        max = len(b)
+    }
+    i := 0
+    for i < max && a[i] == b[i] {
+        i++
+    }
+    return i
+}
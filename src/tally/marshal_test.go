@@ -0,0 +1,105 @@
+package tally
+
+import (
+    "fmt"
+    "math/rand"
+    "reflect"
+    "testing"
+)
+
+func TestSampleAppendTo(t *testing.T) {
+    sample := Sample{key: "requests", value: 42, valueType: COUNTER, sampleRate: 1.0}
+    if got := string(sample.AppendTo(nil)); got != "requests:42|c" {
+        t.Errorf("expected %q, got %q", "requests:42|c", got)
+    }
+}
+
+func TestStatgramMarshalRoundTrip(t *testing.T) {
+    statgram := Statgram{
+        Sample{key: "requests", value: 42, valueType: COUNTER, sampleRate: 1.0},
+        Sample{key: "latency", value: 12.5, valueType: TIMER, sampleRate: 0.5},
+        Sample{key: "queue_depth", value: 5, valueType: GAUGE, sampleRate: 1.0, deltaSign: 1},
+        Sample{key: "users", stringValue: "alice", valueType: SET, sampleRate: 1.0,
+                tags: map[string] string{"region": "us"}},
+    }
+    roundtripped := Parse(string(statgram.Marshal()))
+    if !reflect.DeepEqual(roundtripped, statgram) {
+        t.Errorf("expected %#v, got %#v", statgram, roundtripped)
+    }
+}
+
+// randomStatgram generates a deterministic but varied Statgram for the fuzz
+// test below, keeping keys/tags/set members to characters that don't need
+// the bracket/quote escaping covered separately in split_test.go.
+func randomStatgram(r *rand.Rand, size int) Statgram {
+    alnum := "abcdefghijklmnopqrstuvwxyz0123456789"
+    randomWord := func(n int) string {
+        word := make([]byte, n)
+        for i := range(word) {
+            word[i] = alnum[r.Intn(len(alnum))]
+        }
+        return string(word)
+    }
+
+    statgram := make(Statgram, 0, size)
+    for i := 0; i < size; i++ {
+        sample := Sample{
+            key: randomWord(1 + r.Intn(8)) + "." + randomWord(1 + r.Intn(8)),
+            sampleRate: 1.0,
+            valueType: SampleType(r.Intn(int(METER) + 1)),
+        }
+        if r.Intn(4) == 0 {
+            sample.sampleRate = float64(1 + r.Intn(9)) / 10
+        }
+        if r.Intn(3) == 0 {
+            sample.tags = map[string] string{randomWord(3): randomWord(3)}
+        }
+
+        switch sample.valueType {
+        case SET:
+            sample.stringValue = randomWord(1 + r.Intn(6))
+        case GAUGE:
+            switch r.Intn(3) {
+            case 0:
+                sample.deltaSign = 1
+                sample.value = float64(1 + r.Intn(1000))
+            case 1:
+                sample.deltaSign = -1
+                sample.value = -float64(1 + r.Intn(1000))
+            default:
+                sample.value = float64(r.Intn(1000))
+            }
+        default:
+            sample.value = float64(r.Intn(2000)-1000) / 10
+        }
+        statgram = append(statgram, sample)
+    }
+    return statgram
+}
+
+// FuzzMarshalCompressedRoundTrip asserts Parse(g.MarshalCompressed()) == g
+// across randomly generated statgrams, exercising the "^XX" hex-prefix path's
+// boundary behavior (prefixLength capped at 0xff, and never emitted equal to
+// a fully-repeated previous line) the way a fuzz harness would. seed and size
+// drive randomStatgram rather than fuzzing the statgram directly, since the
+// fuzzer only works with primitive argument types.
+func FuzzMarshalCompressedRoundTrip(f *testing.F) {
+    f.Add(int64(1), 1)
+    f.Add(int64(1), 20)
+    f.Add(int64(42), 0)
+    f.Add(int64(7), 255)
+    f.Fuzz(func(t *testing.T, seed int64, size int) {
+        if size < 0 {
+            size = -size
+        }
+        size %= 32
+
+        r := rand.New(rand.NewSource(seed))
+        statgram := randomStatgram(r, size)
+        roundtripped := Parse(string(statgram.MarshalCompressed()))
+        if !reflect.DeepEqual(roundtripped, statgram) {
+            t.Fatalf("round trip mismatch\nwant: %s\ngot:  %s",
+                    fmt.Sprintf("%#v", statgram), fmt.Sprintf("%#v", roundtripped))
+        }
+    })
+}
@@ -1,31 +1,38 @@
-package tally
+package outputs
 
 import (
     "net"
     "strings"
+
+    ".."
 )
 
 // Graphite is a client for sending stat reports to a graphite (carbon) server.
 type Graphite struct {
     addr *net.TCPAddr
+    alias string // optional prefix prepended to every metric path
 }
 
-func GraphiteFromConfig(config Config) (client *Graphite, err error) {
+func GraphiteFromConfig(config tally.Config) (client *Graphite, err error) {
     var addr string
     addr, err = config.GetValue("graphite", "graphite_addr")
     if err != nil { return }
     client = &Graphite{}
     client.addr, err = net.ResolveTCPAddr("tcp", addr)
+    if err != nil { return }
+    if config.HasValue("graphite", "alias") {
+        client.alias, err = config.GetValue("graphite", "alias")
+    }
     return
 }
 
-// SendReport takes a snapshot and submits all its stats to graphite.
-func (client *Graphite) SendReport(snapshot *Snapshot) (err error) {
+// Publish takes a snapshot and submits all its stats to graphite.
+func (client *Graphite) Publish(snapshot *tally.Snapshot) (err error) {
     var conn *net.TCPConn
     conn, err = net.DialTCP("tcp", nil, client.addr)
     if err != nil { return }
     defer conn.Close()
-    msg := strings.Join(snapshot.GraphiteReport(), "")
+    msg := strings.Join(snapshot.GraphiteReport(client.alias), "")
     _, err = conn.Write([]byte(msg))
     return
 }
@@ -0,0 +1,46 @@
+package outputs
+
+import (
+    "bytes"
+    "fmt"
+    "net/http"
+    "strings"
+
+    ".."
+)
+
+// InfluxDB publishes snapshots to an InfluxDB HTTP write endpoint using line
+// protocol.
+type InfluxDB struct {
+    writeURL string
+    client *http.Client
+}
+
+func InfluxDBFromConfig(config tally.Config) (client *InfluxDB, err error) {
+    var addr, database string
+    if addr, err = config.GetValue("influxdb", "addr"); err != nil { return }
+    if database, err = config.GetValue("influxdb", "database"); err != nil { return }
+    client = &InfluxDB{
+        writeURL: fmt.Sprintf("http://%s/write?db=%s", addr, database),
+        client: &http.Client{},
+    }
+    return
+}
+
+// Publish renders the snapshot as InfluxDB line protocol and POSTs it to the
+// configured database's write endpoint.
+func (client *InfluxDB) Publish(snapshot *tally.Snapshot) (err error) {
+    var buf bytes.Buffer
+    timestamp := snapshot.Timestamp().UnixNano()
+    for key, value := range(snapshot.Counts()) {
+        fmt.Fprintf(&buf, "%s value=%f %d\n", key, value, timestamp)
+    }
+    var resp *http.Response
+    resp, err = client.client.Post(client.writeURL, "text/plain", strings.NewReader(buf.String()))
+    if err != nil { return }
+    defer resp.Body.Close()
+    if resp.StatusCode >= 300 {
+        err = fmt.Errorf("influxdb write failed: %s", resp.Status)
+    }
+    return
+}
@@ -0,0 +1,41 @@
+package outputs
+
+import (
+    "encoding/json"
+
+    "github.com/nats-io/nats.go"
+
+    ".."
+)
+
+// Nats publishes snapshots as JSON to a configurable NATS subject.
+type Nats struct {
+    conn *nats.Conn
+    subject string
+}
+
+func NatsFromConfig(config tally.Config) (client *Nats, err error) {
+    var addr, subject string
+    if addr, err = config.GetValue("nats", "addr"); err != nil { return }
+    if subject, err = config.GetValue("nats", "subject"); err != nil { return }
+    client = &Nats{subject: subject}
+    client.conn, err = nats.Connect(addr)
+    return
+}
+
+type snapshotMessage struct {
+    Timestamp int64 `json:"timestamp"`
+    Stats map[string] float64 `json:"stats"`
+}
+
+// Publish marshals the snapshot to JSON and publishes it to the configured
+// subject.
+func (client *Nats) Publish(snapshot *tally.Snapshot) (err error) {
+    msg := snapshotMessage{
+        Timestamp: snapshot.Timestamp().Unix(),
+        Stats: snapshot.Counts(),
+    }
+    var body []byte
+    if body, err = json.Marshal(msg); err != nil { return }
+    return client.conn.Publish(client.subject, body)
+}
@@ -0,0 +1,38 @@
+package outputs
+
+import (
+    "fmt"
+    "strings"
+
+    ".."
+)
+
+// BuildFromConfig reads the "[tallier] outputs" list and constructs each
+// named sink, each wired from its own section of the same config file.
+// Absent an outputs list, it falls back to a lone graphite sink so existing
+// config files keep working unchanged.
+func BuildFromConfig(config tally.Config) (sinks []tally.OutputSink, err error) {
+    raw, err := config.GetValue("tallier", "outputs")
+    if err != nil {
+        raw = "graphite"
+        err = nil
+    }
+    for _, name := range(strings.Split(raw, ",")) {
+        name = strings.TrimSpace(name)
+        if name == "" { continue }
+        var sink tally.OutputSink
+        switch name {
+        case "graphite":
+            sink, err = GraphiteFromConfig(config)
+        case "influxdb":
+            sink, err = InfluxDBFromConfig(config)
+        case "nats":
+            sink, err = NatsFromConfig(config)
+        default:
+            err = fmt.Errorf("unknown output sink: %#v", name)
+        }
+        if err != nil { return }
+        sinks = append(sinks, sink)
+    }
+    return
+}
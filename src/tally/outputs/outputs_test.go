@@ -0,0 +1,44 @@
+package outputs
+
+import (
+    "testing"
+
+    ".."
+)
+
+func TestBuildFromConfigDefaultsToGraphite(t *testing.T) {
+    config := tally.Config{"graphite": {"graphite_addr": "127.0.0.1:2003"}}
+    sinks, err := BuildFromConfig(config)
+    if err != nil {
+        t.Fatalf("unexpected error: %s", err)
+    }
+    if len(sinks) != 1 {
+        t.Fatalf("expected 1 default sink, got %d", len(sinks))
+    }
+    if _, ok := sinks[0].(*Graphite); !ok {
+        t.Errorf("expected default sink to be *Graphite, got %T", sinks[0])
+    }
+}
+
+func TestBuildFromConfigUnknownSink(t *testing.T) {
+    config := tally.Config{"tallier": {"outputs": "bogus"}}
+    _, err := BuildFromConfig(config)
+    if err == nil {
+        t.Fatal("expected an error for an unknown output sink")
+    }
+}
+
+func TestBuildFromConfigMultipleSinks(t *testing.T) {
+    config := tally.Config{
+        "tallier": {"outputs": "graphite, influxdb"},
+        "graphite": {"graphite_addr": "127.0.0.1:2003"},
+        "influxdb": {"addr": "127.0.0.1:8086", "database": "tallier"},
+    }
+    sinks, err := BuildFromConfig(config)
+    if err != nil {
+        t.Fatalf("unexpected error: %s", err)
+    }
+    if len(sinks) != 2 {
+        t.Fatalf("expected 2 sinks, got %d", len(sinks))
+    }
+}
@@ -0,0 +1,92 @@
+package tally
+
+import (
+    "context"
+    "fmt"
+    "net"
+    "net/http"
+    "sort"
+    "sync"
+    "time"
+)
+
+// PromExporter serves the most recently aggregated snapshot, along with a
+// handful of process-level self-stats, in Prometheus text exposition format
+// so tallier can be scraped instead of (or alongside) pushing to graphite.
+type PromExporter struct {
+    listen string
+    server *http.Server
+
+    mu sync.RWMutex
+    latest *Snapshot
+    messagesTotal int64
+    bytesTotal int64
+    lastFlushDuration time.Duration
+}
+
+func PromExporterFromConfig(config Config) (exporter *PromExporter, err error) {
+    var listen string
+    if listen, err = config.GetValue("prometheus", "listen"); err != nil { return }
+    exporter = &PromExporter{listen: listen}
+    return
+}
+
+// Update records the latest snapshot to be served on the next scrape, rolling
+// its message/byte counts into this exporter's lifetime totals.
+func (exporter *PromExporter) Update(snapshot *Snapshot) {
+    exporter.mu.Lock()
+    defer exporter.mu.Unlock()
+    exporter.latest = snapshot
+    exporter.lastFlushDuration = snapshot.duration
+    exporter.messagesTotal += int64(snapshot.counts["tallier.messages.total"])
+    exporter.bytesTotal += int64(snapshot.counts["tallier.bytes.total"])
+}
+
+// ServeHTTP renders the current stats in Prometheus text exposition format.
+func (exporter *PromExporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+    exporter.mu.RLock()
+    defer exporter.mu.RUnlock()
+
+    fmt.Fprintf(w, "# HELP tallier_messages_total Total statgrams received.\n")
+    fmt.Fprintf(w, "# TYPE tallier_messages_total counter\n")
+    fmt.Fprintf(w, "tallier_messages_total %d\n", exporter.messagesTotal)
+
+    fmt.Fprintf(w, "# HELP tallier_bytes_total Total bytes received.\n")
+    fmt.Fprintf(w, "# TYPE tallier_bytes_total counter\n")
+    fmt.Fprintf(w, "tallier_bytes_total %d\n", exporter.bytesTotal)
+
+    fmt.Fprintf(w, "# HELP tallier_flush_duration_seconds Duration of the most recent aggregation flush.\n")
+    fmt.Fprintf(w, "# TYPE tallier_flush_duration_seconds gauge\n")
+    fmt.Fprintf(w, "tallier_flush_duration_seconds %f\n", exporter.lastFlushDuration.Seconds())
+
+    if exporter.latest == nil { return }
+
+    fmt.Fprintf(w, "# HELP tallier_snapshot_stats Most recently aggregated stat values.\n")
+    fmt.Fprintf(w, "# TYPE tallier_snapshot_stats gauge\n")
+    counts := exporter.latest.Counts()
+    names := make([]string, 0, len(counts))
+    for name := range(counts) {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    for _, name := range(names) {
+        fmt.Fprintf(w, "tallier_snapshot_stats{metric=%#v} %f\n", name, counts[name])
+    }
+}
+
+// Start begins serving /metrics in a background goroutine.
+func (exporter *PromExporter) Start() error {
+    listener, err := net.Listen("tcp", exporter.listen)
+    if err != nil { return err }
+    mux := http.NewServeMux()
+    mux.Handle("/metrics", exporter)
+    exporter.server = &http.Server{Addr: exporter.listen, Handler: mux}
+    go exporter.server.Serve(listener)
+    return nil
+}
+
+// Stop gracefully shuts down the exporter's HTTP server.
+func (exporter *PromExporter) Stop(ctx context.Context) error {
+    if exporter.server == nil { return nil }
+    return exporter.server.Shutdown(ctx)
+}
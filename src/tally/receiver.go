@@ -1,8 +1,11 @@
 package tally
 
 import (
+    "context"
     "log"
     "net"
+    "strconv"
+    "sync"
     "time"
 )
 
@@ -35,10 +38,13 @@ func (receiver *Receiver) ReadOnce() (statgram Statgram, err error) {
 }
 
 // ReceiveStatgrams spins off a goroutine to read statgrams off the UDP port.
-// Returns a buffered channel that will receive statgrams as they arrive.
+// Returns a buffered channel that will receive statgrams as they arrive, and
+// which is closed once the connection errors out (e.g. because it was
+// closed).
 func (receiver *Receiver) ReceiveStatgrams() (statgrams chan Statgram) {
     statgrams = make(chan Statgram, STATGRAM_CHANNEL_BUFSIZE)
     go func() {
+        defer close(statgrams)
         for {
             statgram, err := receiver.ReadOnce()
             if err != nil { break }
@@ -48,10 +54,18 @@ func (receiver *Receiver) ReceiveStatgrams() (statgrams chan Statgram) {
     return
 }
 
-// RunReceiver spins off a goroutine to receive and process statgrams. Returns a
-// bidirectional control channel, which provides a snapshot each time it's given
-// a nil value.
-func RunReceiver(id string, conn *net.UDPConn) (controlChannel chan *Snapshot) {
+// RunReceiver spins off a goroutine to receive and process statgrams. Returns
+// a bidirectional control channel, which provides a snapshot each time it's
+// given a nil value. The goroutine registers itself with wg and returns once
+// drain is closed or its connection is closed out from under it.
+//
+// drain (rather than ctx directly) is what the goroutine watches for exit: if
+// it instead raced ctx.Done() against a pending controlChannel handshake,
+// it could observe cancellation and return while Aggregate is still blocked
+// sending or receiving on controlChannel, hanging Aggregate's shutdown
+// forever. Aggregate only closes drain once it has finished that handshake,
+// so a reply is always delivered before the receiver is allowed to exit.
+func RunReceiver(drain chan struct{}, id string, conn *net.UDPConn, wg *sync.WaitGroup) (controlChannel chan *Snapshot) {
     receiver := &Receiver{
         id: id,
         conn: conn,
@@ -59,12 +73,16 @@ func RunReceiver(id string, conn *net.UDPConn) (controlChannel chan *Snapshot) {
     }
     controlChannel = make(chan *Snapshot)
     statgrams := receiver.ReceiveStatgrams()
+    wg.Add(1)
     go func() {
+        defer wg.Done()
         for {
             select {
-            case statgram := <-statgrams:
+            case statgram, ok := <-statgrams:
+                if !ok { return }
                 receiver.snapshot.ProcessStatgram(statgram)
-            case _ = <-controlChannel:
+            case _, ok := <-controlChannel:
+                if !ok { return }
                 snapshot := receiver.snapshot
                 snapshot.Count("tallier.messages.child_" + receiver.id,
                         float64(receiver.messageCount - receiver.lastMessageCount))
@@ -74,6 +92,8 @@ func RunReceiver(id string, conn *net.UDPConn) (controlChannel chan *Snapshot) {
                 receiver.lastByteCount = receiver.byteCount
                 receiver.snapshot = NewSnapshot()
                 controlChannel <- snapshot
+            case <-drain:
+                return
             }
         }
     }()
@@ -82,22 +102,34 @@ func RunReceiver(id string, conn *net.UDPConn) (controlChannel chan *Snapshot) {
 
 // Aggregate spins off receivers and a goroutine to manage them. Returns a
 // channel by which aggregated snapshots will be shared at the given interval.
-func Aggregate(conn *net.UDPConn, numReceivers int, flushInterval time.Duration) (snapchan chan *Snapshot) {
+// Every snapshot sent on that channel is also fanned out, non-blockingly, to
+// each of subscribers, so other consumers (e.g. a PromExporter) can observe
+// the same flushes without slowing down the primary pipeline. When ctx is
+// cancelled, it drains a final snapshot off the receivers, closes conn, waits
+// for every receiver goroutine to exit, and closes snapchan.
+func Aggregate(ctx context.Context, conn *net.UDPConn, numReceivers int, flushInterval time.Duration, subscribers ...chan *Snapshot) (snapchan chan *Snapshot) {
     snapchan = make(chan *Snapshot)
+    var wg sync.WaitGroup
+    drain := make(chan struct{})
     var controlChannels []chan *Snapshot
     for i := 0; i < numReceivers; i++ {
-        controlChannels = append(controlChannels, RunReceiver(string(i), conn))
+        controlChannels = append(controlChannels, RunReceiver(drain, strconv.Itoa(i), conn, &wg))
     }
 
     go func() {
+        defer close(snapchan)
         var numStats int64 = 0
-        var snapshot *Snapshot
         for {
-            if snapshot != nil { snapchan <- snapshot }
-            snapshot = NewSnapshot()
+            snapshot := NewSnapshot()
             snapshot.start = time.Now()
             log.Printf("aggregator sleeping for %s", flushInterval)
-            time.Sleep(flushInterval)
+            shuttingDown := false
+            select {
+            case <-ctx.Done():
+                log.Printf("aggregator shutting down, draining final snapshot")
+                shuttingDown = true
+            case <-time.After(flushInterval):
+            }
             log.Printf("aggregator sending flush command to receivers")
             for _, controlChannel := range(controlChannels) {
                 controlChannel <- nil
@@ -110,6 +142,19 @@ func Aggregate(conn *net.UDPConn, numReceivers int, flushInterval time.Duration)
             numStats += int64(snapshot.NumStats())
             snapshot.totalStats = numStats
             log.Printf("aggregator returning snapshot")
+            snapchan <- snapshot
+            for _, subscriber := range(subscribers) {
+                select {
+                case subscriber <- snapshot:
+                default:
+                }
+            }
+            if shuttingDown {
+                close(drain)
+                conn.Close()
+                wg.Wait()
+                return
+            }
         }
     }()
     return
@@ -0,0 +1,86 @@
+package tally
+
+import (
+    "fmt"
+    "math/rand"
+    "sort"
+)
+
+// TimerSampleSize is the number of timer values kept per metric by each
+// reservoir. Configurable via "[tallier] timer_sample_size".
+var TimerSampleSize = 8192
+
+// TimerPercentiles are the percentiles reported for each timer metric on
+// flush. Configurable via "[tallier] timer_percentiles".
+var TimerPercentiles = []int{50, 90, 95, 99}
+
+// reservoir keeps a bounded, uniformly-random sample of the values counted
+// into a timer metric using Vitter's Algorithm R, so a metric seeing far more
+// than TimerSampleSize values per flush still yields representative
+// percentiles.
+type reservoir struct {
+    samples []float64
+    count int64 // total number of values ever counted, not just len(samples)
+}
+
+func newReservoir() *reservoir {
+    return &reservoir{samples: make([]float64, 0, TimerSampleSize)}
+}
+
+// Add counts value into the reservoir, replacing a uniformly random existing
+// sample once the reservoir is full.
+func (r *reservoir) Add(value float64) {
+    r.count += 1
+    if len(r.samples) < TimerSampleSize {
+        r.samples = append(r.samples, value)
+        return
+    }
+    if i := rand.Int63n(r.count); i < int64(len(r.samples)) {
+        r.samples[i] = value
+    }
+}
+
+// Merge folds other's sample into r by weighted random selection: once r is
+// full, each of other's samples is kept with probability proportional to the
+// share of the combined population it represents, so a parent snapshot that
+// merges several children still holds a valid sample of size <= N.
+func (r *reservoir) Merge(other *reservoir) {
+    combinedCount := r.count + other.count
+    for _, value := range(other.samples) {
+        if len(r.samples) < TimerSampleSize {
+            r.samples = append(r.samples, value)
+            continue
+        }
+        if combinedCount > 0 && rand.Float64() < float64(other.count)/float64(combinedCount) {
+            r.samples[rand.Intn(len(r.samples))] = value
+        }
+    }
+    r.count = combinedCount
+}
+
+// Stats summarizes the reservoir as count/mean/min/max plus each of the given
+// percentiles (e.g. 50 for p50).
+func (r *reservoir) Stats(percentiles []int) map[string] float64 {
+    stats := map[string] float64{"count": float64(r.count)}
+    if len(r.samples) == 0 {
+        return stats
+    }
+    sorted := append([]float64(nil), r.samples...)
+    sort.Float64s(sorted)
+
+    sum := 0.0
+    for _, value := range(sorted) {
+        sum += value
+    }
+    stats["mean"] = sum / float64(len(sorted))
+    stats["min"] = sorted[0]
+    stats["max"] = sorted[len(sorted) - 1]
+    for _, p := range(percentiles) {
+        index := int(float64(p) / 100 * float64(len(sorted)))
+        if index >= len(sorted) {
+            index = len(sorted) - 1
+        }
+        stats[fmt.Sprintf("p%d", p)] = sorted[index]
+    }
+    return stats
+}
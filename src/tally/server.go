@@ -1,10 +1,13 @@
 package tally
 
 import (
+    "context"
     "fmt"
     "log"
     "net"
     "runtime"
+    "strconv"
+    "sync"
     "time"
 )
 
@@ -15,27 +18,62 @@ type Server struct {
 
     conn *net.UDPConn
     harold *Harold
-    graphite *Graphite
+    outputs []OutputSink
+    baseBackoff time.Duration
+    maxBackoff time.Duration
+    promExporter *PromExporter
+
+    cancel context.CancelFunc
+    done chan struct{}
 }
 
 func ServerFromConfig(config Config) (server *Server, err error) {
-    server = &Server{}
+    defaultBackoff := DefaultBackoff()
+    server = &Server{baseBackoff: defaultBackoff.BaseDelay, maxBackoff: defaultBackoff.MaxDelay}
     server.receiverPort, err = config.GetInt("tallier", "port")
     if err != nil { return }
     server.numWorkers, err = config.GetInt("tallier", "num_workers")
     if err != nil { return }
     server.flushInterval, err = config.GetSeconds("tallier", "flush_interval")
     if err != nil { return }
-    server.graphite, err = GraphiteFromConfig(config)
-    if err != nil { return }
+    if config.HasValue("tallier", "base_backoff") {
+        if server.baseBackoff, err = config.GetSeconds("tallier", "base_backoff"); err != nil { return }
+    }
+    if config.HasValue("tallier", "max_backoff") {
+        if server.maxBackoff, err = config.GetSeconds("tallier", "max_backoff"); err != nil { return }
+    }
+    if config.HasValue("tallier", "timer_sample_size") {
+        var size int
+        if size, err = config.GetInt("tallier", "timer_sample_size"); err != nil { return }
+        TimerSampleSize = size
+    }
+    if config.HasValue("tallier", "timer_percentiles") {
+        var raw []string
+        if raw, err = config.GetStringList("tallier", "timer_percentiles"); err != nil { return }
+        percentiles := make([]int, len(raw))
+        for i, p := range(raw) {
+            if percentiles[i], err = strconv.Atoi(p); err != nil { return }
+        }
+        TimerPercentiles = percentiles
+    }
     var enableHeartbeat bool
     enableHeartbeat, err = config.GetBoolean("tallier", "enable_heartbeat")
     if err == nil && enableHeartbeat {
         server.harold, err = HaroldFromConfig(config)
     }
+    if err == nil && config.HasValue("prometheus", "listen") {
+        server.promExporter, err = PromExporterFromConfig(config)
+    }
     return
 }
 
+// SetOutputs wires up the sinks every flushed snapshot is published to. It's
+// separate from ServerFromConfig so the core package doesn't have to import
+// the concrete sink implementations under tally/outputs.
+func (server *Server) SetOutputs(outputs []OutputSink) {
+    server.outputs = outputs
+}
+
 func (server *Server) Setup() error {
     runtime.GOMAXPROCS(server.numWorkers + 1)
     receiver_addr, err := net.ResolveUDPAddr("udp", fmt.Sprintf(":%d",
@@ -45,29 +83,110 @@ func (server *Server) Setup() error {
     return err
 }
 
-func (server *Server) Loop() {
-    intervals := make(chan time.Duration)
+// Loop runs the server until ctx is cancelled or Shutdown is called, at which
+// point it drains a final snapshot, flushes it to every output, and returns.
+func (server *Server) Loop(ctx context.Context) {
+    ctx, server.cancel = context.WithCancel(ctx)
+    server.done = make(chan struct{})
+    defer close(server.done)
+
     log.Printf("setting up server")
     server.Setup()
+    var intervals chan time.Duration
     if server.harold != nil {
-        go server.harold.HeartMonitor("tallier", intervals)
+        intervals = server.harold.HeartMonitor(ctx, "tallier")
     }
-    snapchan := Aggregate(server.conn, server.numWorkers, server.flushInterval)
+
+    var subscribers []chan *Snapshot
+    if server.promExporter != nil {
+        if err := server.promExporter.Start(); err != nil {
+            log.Printf("ERROR: failed to start prometheus exporter: %s", err)
+        } else {
+            defer server.promExporter.Stop(context.Background())
+            promSnapshots := make(chan *Snapshot, 1)
+            defer close(promSnapshots)
+            go func() {
+                for snapshot := range(promSnapshots) {
+                    server.promExporter.Update(snapshot)
+                }
+            }()
+            subscribers = append(subscribers, promSnapshots)
+        }
+    }
+
+    // Each sink gets its own queue and persistent worker, so a sink that's
+    // down and retrying with backoff can never stall snapshot consumption:
+    // Loop only ever does a non-blocking send into the queue, dropping (and
+    // logging) a snapshot if the sink hasn't caught up yet.
+    var sinkWG sync.WaitGroup
+    sinkQueues := make([]chan *Snapshot, len(server.outputs))
+    for i, sink := range(server.outputs) {
+        queue := make(chan *Snapshot, 1)
+        sinkQueues[i] = queue
+        sinkWG.Add(1)
+        go func(sink OutputSink, queue chan *Snapshot) {
+            defer sinkWG.Done()
+            backoff := &Backoff{
+                BaseDelay: server.baseBackoff,
+                MaxDelay: server.maxBackoff,
+                Factor: 1.6,
+                Jitter: 0.2,
+            }
+            for snapshot := range(queue) {
+                for {
+                    err := sink.Publish(snapshot)
+                    if err == nil {
+                        backoff.Reset()
+                        break
+                    }
+                    log.Printf("ERROR: failed to publish snapshot: %s", err)
+                    select {
+                    case <-time.After(backoff.Next()):
+                    case <-ctx.Done():
+                        return
+                    }
+                }
+            }
+        }(sink, queue)
+    }
+
+    snapchan := Aggregate(ctx, server.conn, server.numWorkers, server.flushInterval, subscribers...)
     log.Printf("running")
-    for {
-        log.Printf("waiting for snapshot")
-        snapshot := <-snapchan
-        for {
-            log.Printf("sending snapshot with %d stats to graphite",
-                    snapshot.NumStats())
-            var err error
-            if err = server.graphite.SendReport(snapshot); err == nil { break }
-            log.Printf("ERROR: failed to send graphite report: %s", err)
-            time.Sleep(time.Second)
+    for snapshot := range(snapchan) {
+        log.Printf("publishing snapshot with %d stats to %d output(s)",
+                snapshot.NumStats(), len(server.outputs))
+        for i, queue := range(sinkQueues) {
+            select {
+            case queue <- snapshot:
+            default:
+                log.Printf("WARNING: output %d is still busy, dropping snapshot", i)
+            }
         }
-        if server.harold != nil {
+        if intervals != nil {
             log.Printf("sending interval to heart monitor")
-            intervals <- 3 * server.flushInterval
+            select {
+            case intervals <- 3 * server.flushInterval:
+            case <-ctx.Done():
+            }
         }
     }
+    for _, queue := range(sinkQueues) {
+        close(queue)
+    }
+    sinkWG.Wait()
+    log.Printf("server loop exiting")
+}
+
+// Shutdown cancels the running Loop and blocks until it has finished
+// draining and flushing its final snapshot, or until ctx is done.
+func (server *Server) Shutdown(ctx context.Context) error {
+    if server.cancel != nil {
+        server.cancel()
+    }
+    select {
+    case <-server.done:
+        return nil
+    case <-ctx.Done():
+        return ctx.Err()
+    }
 }
@@ -0,0 +1,9 @@
+package tally
+
+// OutputSink receives aggregated snapshots and ships them to a downstream
+// metrics backend. Concrete implementations live under tally/outputs so the
+// core aggregation pipeline doesn't depend on any particular backend's
+// client library.
+type OutputSink interface {
+    Publish(snapshot *Snapshot) error
+}
@@ -0,0 +1,211 @@
+package tally
+
+import (
+    "fmt"
+    "strings"
+    "time"
+)
+
+// Snapshot accumulates the stats counted over a single flush interval so they
+// can be merged across receivers and reported to graphite.
+type Snapshot struct {
+    counts map[string] float64
+    timers map[string] *reservoir
+    gauges map[string] float64
+    sets map[string] map[string] struct{}
+    numChildren int
+    totalStats int64
+    start time.Time
+    duration time.Duration
+}
+
+// NewSnapshot returns an empty snapshot ready to be counted into.
+func NewSnapshot() *Snapshot {
+    return &Snapshot{counts: make(map[string] float64)}
+}
+
+// Count adds value to the running total kept under key, creating it if it
+// doesn't already exist.
+func (snapshot *Snapshot) Count(key string, value float64) {
+    snapshot.counts[key] += value
+}
+
+// Time records a value (e.g. in milliseconds) into the bounded sample
+// reservoir kept for key, creating it if it doesn't already exist.
+func (snapshot *Snapshot) Time(key string, value float64) {
+    if snapshot.timers == nil {
+        snapshot.timers = make(map[string] *reservoir)
+    }
+    r, ok := snapshot.timers[key]
+    if !ok {
+        r = newReservoir()
+        snapshot.timers[key] = r
+    }
+    r.Add(value)
+}
+
+// Gauge records a gauge reading for key. A deltaSign of 0 replaces the
+// reading outright (an absolute "5|g"); +1/-1 instead adds value to whatever
+// reading this snapshot already holds for key this interval (a delta "+5|g"
+// or "-5|g"). Because a Snapshot only lives for a single flush interval, a
+// delta is relative to the last reading seen *this interval*, starting from
+// zero, not to the gauge's true last-reported value from prior intervals.
+func (snapshot *Snapshot) Gauge(key string, value float64, deltaSign int8) {
+    if snapshot.gauges == nil {
+        snapshot.gauges = make(map[string] float64)
+    }
+    if deltaSign == 0 {
+        snapshot.gauges[key] = value
+    } else {
+        snapshot.gauges[key] += value
+    }
+}
+
+// Unique records member as having been seen for the set kept under key,
+// creating it if it doesn't already exist. Sets report the count of distinct
+// members seen per flush interval, the standard statsd "|s" semantics.
+func (snapshot *Snapshot) Unique(key string, member string) {
+    if snapshot.sets == nil {
+        snapshot.sets = make(map[string] map[string] struct{})
+    }
+    set, ok := snapshot.sets[key]
+    if !ok {
+        set = make(map[string] struct{})
+        snapshot.sets[key] = set
+    }
+    set[member] = struct{}{}
+}
+
+// NumStats returns the number of distinct stats this snapshot holds.
+func (snapshot *Snapshot) NumStats() int {
+    return len(snapshot.counts) + len(snapshot.timers) + len(snapshot.gauges) + len(snapshot.sets)
+}
+
+// Aggregate merges another snapshot's counts, timer reservoirs, gauges, and
+// sets into this one, rolling up each receiver child's message/byte counters
+// into a running total. Since a gauge's "current reading" isn't well-defined
+// across sibling receivers, the last child merged wins, the same ordering
+// ambiguity statsd's own cluster aggregation has.
+func (snapshot *Snapshot) Aggregate(other *Snapshot) {
+    for key, value := range(other.counts) {
+        snapshot.Count(key, value)
+        if strings.HasPrefix(key, "tallier.messages.child_") {
+            snapshot.Count("tallier.messages.total", value)
+        } else if strings.HasPrefix(key, "tallier.bytes.child_") {
+            snapshot.Count("tallier.bytes.total", value)
+        }
+    }
+    for key, otherReservoir := range(other.timers) {
+        if snapshot.timers == nil {
+            snapshot.timers = make(map[string] *reservoir)
+        }
+        r, ok := snapshot.timers[key]
+        if !ok {
+            r = newReservoir()
+            snapshot.timers[key] = r
+        }
+        r.Merge(otherReservoir)
+    }
+    for key, value := range(other.gauges) {
+        if snapshot.gauges == nil {
+            snapshot.gauges = make(map[string] float64)
+        }
+        snapshot.gauges[key] = value
+    }
+    for key, otherMembers := range(other.sets) {
+        if snapshot.sets == nil {
+            snapshot.sets = make(map[string] map[string] struct{})
+        }
+        members, ok := snapshot.sets[key]
+        if !ok {
+            members = make(map[string] struct{})
+            snapshot.sets[key] = members
+        }
+        for member := range(otherMembers) {
+            members[member] = struct{}{}
+        }
+    }
+    snapshot.numChildren += 1
+}
+
+// ProcessStatgram counts every sample in the statgram into the snapshot,
+// expanding any DogStatsD-style tags into a distinct graphite path. Timers
+// and histograms are kept in a reservoir for percentile reporting, gauges
+// and sets get their own dedicated aggregation, and everything else
+// (counters and meters) is counted directly, extrapolated by sampleRate.
+func (snapshot *Snapshot) ProcessStatgram(statgram Statgram) {
+    for _, sample := range(statgram) {
+        key := sample.TagKey()
+        switch sample.valueType {
+        case TIMER, HISTOGRAM:
+            snapshot.Time(key, sample.value)
+        case GAUGE:
+            snapshot.Gauge(key, sample.value, sample.deltaSign)
+        case SET:
+            snapshot.Unique(key, sample.stringValue)
+        default:
+            snapshot.Count(key, sample.value / sample.sampleRate)
+        }
+    }
+}
+
+// Counts returns a copy of every stat value held by this snapshot, keyed by
+// metric path. Timer reservoirs are flattened the same way GraphiteReport
+// renders them: "key.count", "key.mean", "key.min", "key.max", and
+// "key.pNN" for each of TimerPercentiles. Gauges report their current
+// reading directly; sets report the count of distinct members seen.
+func (snapshot *Snapshot) Counts() map[string] float64 {
+    counts := make(map[string] float64, len(snapshot.counts) + len(snapshot.gauges) + len(snapshot.sets))
+    for key, value := range(snapshot.counts) {
+        counts[key] = value
+    }
+    for key, r := range(snapshot.timers) {
+        for name, value := range(r.Stats(TimerPercentiles)) {
+            counts[key + "." + name] = value
+        }
+    }
+    for key, value := range(snapshot.gauges) {
+        counts[key] = value
+    }
+    for key, members := range(snapshot.sets) {
+        counts[key] = float64(len(members))
+    }
+    return counts
+}
+
+// Timestamp returns the time this snapshot's collection interval began.
+func (snapshot *Snapshot) Timestamp() time.Time {
+    return snapshot.start
+}
+
+// GraphiteReport renders every stat as a graphite plaintext protocol line,
+// prefixing each metric path with alias (if non-empty). Timer and histogram
+// metrics are expanded into their count/mean/min/max/percentile lines;
+// gauges report their current reading and sets their distinct member count.
+func (snapshot *Snapshot) GraphiteReport(alias string) (lines []string) {
+    timestamp := snapshot.start.Unix()
+    lines = make([]string, 0, len(snapshot.counts) + len(snapshot.timers) * (4 + len(TimerPercentiles)) +
+            len(snapshot.gauges) + len(snapshot.sets))
+    for key, value := range(snapshot.counts) {
+        lines = append(lines, graphiteLine(alias, key, value, timestamp))
+    }
+    for key, r := range(snapshot.timers) {
+        for name, value := range(r.Stats(TimerPercentiles)) {
+            lines = append(lines, graphiteLine(alias, key + "." + name, value, timestamp))
+        }
+    }
+    for key, value := range(snapshot.gauges) {
+        lines = append(lines, graphiteLine(alias, key, value, timestamp))
+    }
+    for key, members := range(snapshot.sets) {
+        lines = append(lines, graphiteLine(alias, key, float64(len(members)), timestamp))
+    }
+    return
+}
+
+func graphiteLine(alias string, key string, value float64, timestamp int64) string {
+    if alias != "" {
+        key = alias + "." + key
+    }
+    return fmt.Sprintf("%s %f %d\n", key, value, timestamp)
+}
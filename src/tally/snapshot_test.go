@@ -35,3 +35,110 @@ func TestSnapshots(t *testing.T) {
 		t.Errorf("expected %#v, got %#v", expected, parent)
 	}
 }
+
+func TestSnapshotTimers(t *testing.T) {
+	snapshot := NewSnapshot()
+	for _, value := range([]float64{1, 2, 3, 4, 5}) {
+		snapshot.Time("request", value)
+	}
+
+	stats := snapshot.timers["request"].Stats([]int{50})
+	if stats["count"] != 5 {
+		t.Errorf("expected count 5, got %f", stats["count"])
+	}
+	if stats["min"] != 1 || stats["max"] != 5 {
+		t.Errorf("expected min 1, max 5, got min %f, max %f", stats["min"], stats["max"])
+	}
+	if stats["mean"] != 3 {
+		t.Errorf("expected mean 3, got %f", stats["mean"])
+	}
+	if stats["p50"] != 3 {
+		t.Errorf("expected p50 3, got %f", stats["p50"])
+	}
+}
+
+func TestSnapshotTimersMerge(t *testing.T) {
+	parent := NewSnapshot()
+	a := NewSnapshot()
+	b := NewSnapshot()
+	a.Time("request", 10)
+	b.Time("request", 20)
+	parent.Aggregate(a)
+	parent.Aggregate(b)
+
+	stats := parent.timers["request"].Stats(nil)
+	if stats["count"] != 2 {
+		t.Errorf("expected count 2, got %f", stats["count"])
+	}
+}
+
+func TestSnapshotGaugeAbsoluteAndDelta(t *testing.T) {
+	snapshot := NewSnapshot()
+	snapshot.Gauge("queue_depth", 10, 0)
+	if snapshot.gauges["queue_depth"] != 10 {
+		t.Errorf("expected 10, got %f", snapshot.gauges["queue_depth"])
+	}
+	snapshot.Gauge("queue_depth", 5, 1)
+	if snapshot.gauges["queue_depth"] != 15 {
+		t.Errorf("expected delta to add to 15, got %f", snapshot.gauges["queue_depth"])
+	}
+	snapshot.Gauge("queue_depth", -3, -1)
+	if snapshot.gauges["queue_depth"] != 12 {
+		t.Errorf("expected delta to subtract to 12, got %f", snapshot.gauges["queue_depth"])
+	}
+	snapshot.Gauge("queue_depth", 100, 0)
+	if snapshot.gauges["queue_depth"] != 100 {
+		t.Errorf("expected absolute set to replace reading, got %f", snapshot.gauges["queue_depth"])
+	}
+}
+
+func TestSnapshotSetCardinality(t *testing.T) {
+	snapshot := NewSnapshot()
+	snapshot.Unique("uniques", "alice")
+	snapshot.Unique("uniques", "bob")
+	snapshot.Unique("uniques", "alice")
+	if len(snapshot.sets["uniques"]) != 2 {
+		t.Errorf("expected 2 distinct members, got %d", len(snapshot.sets["uniques"]))
+	}
+	if snapshot.Counts()["uniques"] != 2 {
+		t.Errorf("expected Counts to report cardinality 2, got %f", snapshot.Counts()["uniques"])
+	}
+}
+
+func TestSnapshotSetMerge(t *testing.T) {
+	parent := NewSnapshot()
+	a := NewSnapshot()
+	b := NewSnapshot()
+	a.Unique("uniques", "alice")
+	b.Unique("uniques", "bob")
+	b.Unique("uniques", "alice")
+	parent.Aggregate(a)
+	parent.Aggregate(b)
+	if len(parent.sets["uniques"]) != 2 {
+		t.Errorf("expected 2 distinct members after merge, got %d", len(parent.sets["uniques"]))
+	}
+}
+
+func TestProcessStatgramGaugeIgnoresSampleRate(t *testing.T) {
+	snapshot := NewSnapshot()
+	statgram, err := ParseStatgramLine("queue_depth:10|g@0.5")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	snapshot.ProcessStatgram(statgram)
+	if snapshot.gauges["queue_depth"] != 10 {
+		t.Errorf("expected sampleRate to be ignored for gauges, got %f", snapshot.gauges["queue_depth"])
+	}
+}
+
+func TestProcessStatgramSet(t *testing.T) {
+	snapshot := NewSnapshot()
+	statgram, err := ParseStatgramLine(`users:"alice"|s:"bob"|s`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	snapshot.ProcessStatgram(statgram)
+	if len(snapshot.sets["users"]) != 2 {
+		t.Errorf("expected 2 distinct members, got %d", len(snapshot.sets["users"]))
+	}
+}
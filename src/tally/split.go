@@ -0,0 +1,54 @@
+package tally
+
+// splitTopLevel splits s on sep, but only at positions where the bracket
+// nesting depth of (), [], and {} is zero, s isn't inside a "..." quoted
+// string, and s isn't inside a trailing "#tag1:v1,tag2:v2" tag section (which
+// runs from an unbracketed '#' to the next '|', or to the end of s). This
+// lets ParseStatgramLine and ParseSample keep splitting on ':' and '|'
+// without being corrupted by a tag value, set member, or annotation that
+// happens to contain one of those characters, e.g.
+// "svc{region:us,env:prod}:42|c", `users:"a:b"|s`, or
+// "requests:42|c|#env:prod,region:us".
+func splitTopLevel(s string, sep byte) []string {
+    parts := make([]string, 0, 2)
+    depth := 0
+    inQuote := false
+    inTag := false
+    start := 0
+    for i := 0; i < len(s); i++ {
+        switch c := s[i]; {
+        case inTag:
+            if c == '|' {
+                inTag = false
+            }
+        case inQuote:
+            if c == '"' {
+                inQuote = false
+            }
+        case c == '"':
+            inQuote = true
+        case c == '#' && depth == 0:
+            inTag = true
+        case c == '(' || c == '[' || c == '{':
+            depth++
+        case c == ')' || c == ']' || c == '}':
+            if depth > 0 {
+                depth--
+            }
+        case c == sep && depth == 0:
+            parts = append(parts, s[start:i])
+            start = i + 1
+        }
+    }
+    parts = append(parts, s[start:])
+    return parts
+}
+
+// unquote strips a single matching pair of surrounding double quotes from s,
+// if present, so a quoted set member like `"a:b"` is stored as `a:b`.
+func unquote(s string) string {
+    if len(s) >= 2 && s[0] == '"' && s[len(s) - 1] == '"' {
+        return s[1:len(s) - 1]
+    }
+    return s
+}
@@ -0,0 +1,63 @@
+package tally
+
+import "testing"
+
+func TestSplitTopLevelBrackets(t *testing.T) {
+    parts := splitTopLevel("svc{region:us,env:prod}:42", ':')
+    if len(parts) != 2 || parts[0] != "svc{region:us,env:prod}" || parts[1] != "42" {
+        t.Errorf("unexpected split: %#v", parts)
+    }
+}
+
+func TestSplitTopLevelQuotes(t *testing.T) {
+    parts := splitTopLevel(`users:"a:b"`, ':')
+    if len(parts) != 2 || parts[0] != "users" || parts[1] != `"a:b"` {
+        t.Errorf("unexpected split: %#v", parts)
+    }
+}
+
+func TestParseStatgramLineBracketedKey(t *testing.T) {
+    statgram, err := ParseStatgramLine("svc{region:us,env:prod}:42|c")
+    if err != nil {
+        t.Fatalf("unexpected error: %s", err)
+    }
+    if len(statgram) != 1 || statgram[0].key != "svc{region:us,env:prod}" {
+        t.Errorf("unexpected statgram: %#v", statgram)
+    }
+    if statgram[0].value != 42 || statgram[0].valueType != COUNTER {
+        t.Errorf("unexpected sample: %#v", statgram[0])
+    }
+}
+
+func TestSplitTopLevelTagSection(t *testing.T) {
+    parts := splitTopLevel("requests:42|c|#env:prod,region:us", ':')
+    if len(parts) != 2 || parts[0] != "requests" || parts[1] != "42|c|#env:prod,region:us" {
+        t.Errorf("unexpected split: %#v", parts)
+    }
+}
+
+func TestParseStatgramLineTaggedSample(t *testing.T) {
+    statgram, err := ParseStatgramLine("requests:42|c|#env:prod,region:us")
+    if err != nil {
+        t.Fatalf("unexpected error: %s", err)
+    }
+    if len(statgram) != 1 || statgram[0].key != "requests" {
+        t.Errorf("unexpected statgram: %#v", statgram)
+    }
+    if statgram[0].tags["env"] != "prod" || statgram[0].tags["region"] != "us" {
+        t.Errorf("unexpected tags: %#v", statgram[0].tags)
+    }
+}
+
+func TestParseStatgramLineQuotedSetMember(t *testing.T) {
+    statgram, err := ParseStatgramLine(`users:"a:b"|s`)
+    if err != nil {
+        t.Fatalf("unexpected error: %s", err)
+    }
+    if len(statgram) != 1 || statgram[0].valueType != SET {
+        t.Errorf("unexpected statgram: %#v", statgram)
+    }
+    if statgram[0].stringValue != "a:b" {
+        t.Errorf("expected stringValue %q, got %q", "a:b", statgram[0].stringValue)
+    }
+}
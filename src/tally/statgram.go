@@ -1,7 +1,11 @@
 package tally
 
 import (
+    "bufio"
     "errors"
+    "fmt"
+    "io"
+    "sort"
     "strconv"
     "strings"
 )
@@ -10,13 +14,42 @@ type SampleType int
 const (
     COUNTER SampleType = iota
     TIMER
+    GAUGE
+    HISTOGRAM
+    SET
+    METER
 )
 
 type Sample struct {
     key string
     value float64
+    stringValue string // the member counted, for SET samples only
     valueType SampleType
     sampleRate float64
+    // deltaSign is only meaningful for GAUGE samples: 0 means value is an
+    // absolute set (e.g. "5|g"), +1/-1 means value is a signed delta to
+    // apply to the gauge's current reading (e.g. "+5|g" or "-5|g").
+    deltaSign int8
+    tags map[string] string
+}
+
+// TagKey returns the graphite path for this sample: its key with each tag
+// appended as a "name_value" segment, sorted by tag name so the same tagset
+// always produces the same path. Untagged samples are returned unchanged.
+func (sample Sample) TagKey() string {
+    if len(sample.tags) == 0 {
+        return sample.key
+    }
+    names := make([]string, 0, len(sample.tags))
+    for name := range(sample.tags) {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    key := sample.key
+    for _, name := range(names) {
+        key += "." + name + "_" + sample.tags[name]
+    }
+    return key
 }
 
 type Statgram []Sample
@@ -47,8 +80,77 @@ func Parse(text string) (statgram Statgram) {
     return
 }
 
+// ParseReader streams samples from r as records arrive, so a UDP packet
+// handler or a long-lived TCP stream doesn't have to buffer the whole input
+// the way Parse does. Records may be separated by '\n', ';', or ',', which
+// lets a single datagram carry multiple statgrams without embedded newlines.
+// The "^XX" prefix-compression escape is still honored across records, by
+// keeping the previous record as scanner state exactly as Parse does.
+//
+// Parse errors are sent on the returned error channel without stopping the
+// scan; the sample channel is closed when r is exhausted or returns an error.
+// The error channel is a best-effort diagnostic, not a backpressure signal: a
+// caller that only drains samples (the natural way to consume two returned
+// channels) must never be able to deadlock the producer, so a send that
+// would block because nobody's reading errs is dropped rather than blocking.
+func ParseReader(r io.Reader) (samples <-chan Sample, errs <-chan error) {
+    sampleChan := make(chan Sample)
+    errChan := make(chan error, 1)
+    samples = sampleChan
+    errs = errChan
+    go func() {
+        defer close(sampleChan)
+        defer close(errChan)
+        scanner := bufio.NewScanner(r)
+        scanner.Split(splitStatgramRecords)
+        previous := ""
+        for scanner.Scan() {
+            value := scanner.Text()
+            if len(value) > 2 && value[0] == '^' {
+                prefixLength, err := strconv.ParseInt(value[1:3], 16, 0)
+                if err == nil && int(prefixLength) < len(previous) {
+                    value = previous[:prefixLength] + value[3:]
+                }
+            }
+            previous = value
+            subsamples, err := ParseStatgramLine(value)
+            if err != nil {
+                select {
+                case errChan <- err:
+                default:
+                }
+                continue
+            }
+            for _, sample := range(subsamples) {
+                sampleChan <- sample
+            }
+        }
+        if err := scanner.Err(); err != nil {
+            select {
+            case errChan <- err:
+            default:
+            }
+        }
+    }()
+    return
+}
+
+// splitStatgramRecords is a bufio.SplitFunc that treats '\n', ';', and ',' as
+// record separators.
+func splitStatgramRecords(data []byte, atEOF bool) (advance int, token []byte, err error) {
+    for i, b := range(data) {
+        if b == '\n' || b == ';' || b == ',' {
+            return i + 1, data[:i], nil
+        }
+    }
+    if atEOF && len(data) > 0 {
+        return len(data), data, nil
+    }
+    return 0, nil, nil
+}
+
 func ParseStatgramLine(text string) (statgram Statgram, err error) {
-    parts := strings.Split(text, ":")
+    parts := splitTopLevel(text, ':')
     if len(parts) == 0 {
         return
     }
@@ -66,28 +168,79 @@ func ParseStatgramLine(text string) (statgram Statgram, err error) {
 }
 
 func ParseSample(key string, part string) (sample Sample, err error) {
-    fields := strings.Split(part, "|")
-    if len(fields) != 2 {
-        err = errors.New("sample field should contain exactly one '|'")
+    fields := splitTopLevel(part, '|')
+    if len(fields) < 2 || len(fields) > 3 {
+        err = errors.New("sample field should contain one or two '|'")
         return
     }
-    var value float64
-    if value, err = strconv.ParseFloat(fields[0], 64); err != nil {
-        return
-    }
-    sample = Sample{key: key, value: value, sampleRate: 1.0}
-    if strings.Contains(fields[1], "@") {
-        f1Parts := strings.SplitN(fields[1], "@", 2)
-        fields[1] = f1Parts[0]
-        sample.sampleRate, err = strconv.ParseFloat(f1Parts[1], 64)
-        if err != nil {
+    sample = Sample{key: key, sampleRate: 1.0}
+
+    typeField := fields[1]
+    if strings.Contains(typeField, "@") {
+        f1Parts := strings.SplitN(typeField, "@", 2)
+        typeField = f1Parts[0]
+        if sample.sampleRate, err = strconv.ParseFloat(f1Parts[1], 64); err != nil {
             return
         }
     }
-    if fields[1] == "ms" {
+    switch typeField {
+    case "ms":
         sample.valueType = TIMER
-    } else {
+    case "c":
         sample.valueType = COUNTER
+    case "g":
+        sample.valueType = GAUGE
+    case "h":
+        sample.valueType = HISTOGRAM
+    case "s":
+        sample.valueType = SET
+    case "m":
+        sample.valueType = METER
+    default:
+        err = fmt.Errorf("unknown sample type: %#v", typeField)
+        return
+    }
+
+    if sample.valueType == SET {
+        sample.stringValue = unquote(fields[0])
+    } else {
+        if sample.valueType == GAUGE && len(fields[0]) > 0 &&
+                (fields[0][0] == '+' || fields[0][0] == '-') {
+            if fields[0][0] == '+' {
+                sample.deltaSign = 1
+            } else {
+                sample.deltaSign = -1
+            }
+        }
+        if sample.value, err = strconv.ParseFloat(fields[0], 64); err != nil {
+            return
+        }
+    }
+
+    if len(fields) == 3 {
+        if sample.tags, err = parseTags(fields[2]); err != nil {
+            return
+        }
+    }
+    return
+}
+
+// parseTags parses a DogStatsD-style "#tag1:v1,tag2:v2" section into a tag
+// map. A tag with no ":value" part is stored with an empty value.
+func parseTags(field string) (tags map[string] string, err error) {
+    if len(field) == 0 || field[0] != '#' {
+        err = errors.New("tag section must begin with '#'")
+        return
+    }
+    tags = make(map[string] string)
+    for _, pair := range(strings.Split(field[1:], ",")) {
+        if pair == "" { continue }
+        kv := strings.SplitN(pair, ":", 2)
+        if len(kv) == 2 {
+            tags[kv[0]] = kv[1]
+        } else {
+            tags[kv[0]] = ""
+        }
     }
     return
 }
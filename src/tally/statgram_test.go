@@ -0,0 +1,36 @@
+package tally
+
+import (
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestParseReaderMultipleErrorsDoNotDeadlock(t *testing.T) {
+    samples, _ := ParseReader(strings.NewReader("key:5|zz\nkey:5|zz\nkey:5|zz\n"))
+    done := make(chan struct{})
+    go func() {
+        for range(samples) {
+        }
+        close(done)
+    }()
+    select {
+    case <-done:
+    case <-time.After(2 * time.Second):
+        t.Fatal("ParseReader deadlocked when only the samples channel was drained")
+    }
+}
+
+func TestParseReaderYieldsSamples(t *testing.T) {
+    samples, errs := ParseReader(strings.NewReader("a:1|c\nb:2|c\n"))
+    var got []Sample
+    for sample := range(samples) {
+        got = append(got, sample)
+    }
+    for err := range(errs) {
+        t.Errorf("unexpected error: %s", err)
+    }
+    if len(got) != 2 || got[0].key != "a" || got[1].key != "b" {
+        t.Errorf("unexpected samples: %#v", got)
+    }
+}